@@ -0,0 +1,436 @@
+package mixpanel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Mixpanel's batch endpoints cap a single request at 50 events and ~2MB of
+// JSON. See https://developer.mixpanel.com/reference/import-events.
+const (
+	defaultMaxBatchSize   = 50
+	defaultMaxBufferBytes = 2 * 1024 * 1024
+	defaultFlushInterval  = 10 * time.Second
+)
+
+// BufferedConfig configures a BufferedMixpanel. Any zero-valued field falls
+// back to a sane default.
+type BufferedConfig struct {
+	// MaxBatchSize is the most events sent in a single /track, /engage or
+	// /groups batch request. Defaults to 50.
+	MaxBatchSize int
+
+	// MaxBufferBytes is the largest a single batch's JSON payload is allowed
+	// to grow before it is split. Defaults to 2MB.
+	MaxBufferBytes int
+
+	// FlushInterval is how often queued events are flushed automatically in
+	// the background. Defaults to 10s.
+	FlushInterval time.Duration
+}
+
+// BufferedMixpanel wraps a Mixpanel client and queues Track, UpdateUser,
+// UnionUser, UpdateGroup, UnionGroup and Alias calls in memory, flushing them
+// in batches on Mixpanel's batch-import endpoints instead of issuing one HTTP
+// request per call. This is analogous to the official client libraries'
+// "buffered consumer". Import is not batched and is forwarded immediately.
+//
+// A BufferedMixpanel is safe for concurrent use. Call Close (or Flush
+// explicitly) during shutdown to make sure nothing is left queued.
+type BufferedMixpanel struct {
+	*mixpanel
+
+	cfg BufferedConfig
+
+	mu             sync.Mutex
+	events         []map[string]interface{}
+	profileUpdates []map[string]interface{}
+	groupUpdates   []map[string]interface{}
+
+	ticker *time.Ticker
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+var _ Mixpanel = (*BufferedMixpanel)(nil)
+
+// NewBuffered returns a BufferedMixpanel. If apiURL is blank, the default
+// will be used ("https://api.mixpanel.com"). opts configures optional
+// behaviour such as WithRetry, WithTimeout and WithSecret, same as New.
+func NewBuffered(token, apiURL string, cfg BufferedConfig, opts ...Option) *BufferedMixpanel {
+	return NewBufferedFromClient(http.DefaultClient, token, apiURL, cfg, opts...)
+}
+
+// NewBufferedFromClient is NewBuffered using the specified client instance.
+// This is useful when using a proxy or pointing at a test server.
+func NewBufferedFromClient(c *http.Client, token, apiURL string, cfg BufferedConfig, opts ...Option) *BufferedMixpanel {
+	if apiURL == "" {
+		apiURL = "https://api.mixpanel.com"
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = defaultMaxBatchSize
+	}
+	if cfg.MaxBufferBytes <= 0 {
+		cfg.MaxBufferBytes = defaultMaxBufferBytes
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+
+	m := &mixpanel{
+		Client: c,
+		Token:  token,
+		ApiURL: apiURL,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	bm := &BufferedMixpanel{
+		mixpanel: m,
+		cfg:      cfg,
+		stop:     make(chan struct{}),
+	}
+
+	bm.ticker = time.NewTicker(cfg.FlushInterval)
+	bm.wg.Add(1)
+	go bm.flushLoop()
+
+	return bm
+}
+
+func (bm *BufferedMixpanel) flushLoop() {
+	defer bm.wg.Done()
+	for {
+		select {
+		case <-bm.ticker.C:
+			_ = bm.Flush(context.Background())
+		case <-bm.stop:
+			return
+		}
+	}
+}
+
+// Close stops the background flush goroutine and flushes anything still
+// queued. It should be called once during shutdown.
+func (bm *BufferedMixpanel) Close(ctx context.Context) error {
+	bm.ticker.Stop()
+	close(bm.stop)
+	bm.wg.Wait()
+	return bm.Flush(ctx)
+}
+
+// Track queues an event to be sent in the next /track batch.
+func (bm *BufferedMixpanel) Track(distinctId, eventName string, e *Event) error {
+	props := map[string]interface{}{
+		"token":       bm.Token,
+		"distinct_id": distinctId,
+	}
+	if e.IP != "" {
+		props["ip"] = e.IP
+	}
+	if e.Timestamp != nil {
+		props["time"] = e.Timestamp.Unix()
+	}
+	for key, value := range e.Properties {
+		props[key] = value
+	}
+
+	bm.mu.Lock()
+	bm.events = append(bm.events, map[string]interface{}{
+		"event":      eventName,
+		"properties": props,
+	})
+	bm.mu.Unlock()
+
+	return nil
+}
+
+// Alias queues a $create_alias event to be sent in the next /track batch.
+func (bm *BufferedMixpanel) Alias(distinctId, newId string) error {
+	props := map[string]interface{}{
+		"token":       bm.Token,
+		"distinct_id": distinctId,
+		"alias":       newId,
+	}
+
+	bm.mu.Lock()
+	bm.events = append(bm.events, map[string]interface{}{
+		"event":      "$create_alias",
+		"properties": props,
+	})
+	bm.mu.Unlock()
+
+	return nil
+}
+
+// UpdateUser queues a profile update to be sent in the next /engage batch.
+func (bm *BufferedMixpanel) UpdateUser(distinctId string, u *Update) error {
+	params := map[string]interface{}{
+		"$token":       bm.Token,
+		"$distinct_id": distinctId,
+	}
+	if u.IP != "" {
+		params["$ip"] = u.IP
+	}
+	if u.Timestamp == IgnoreTime {
+		params["$ignore_time"] = true
+	} else if u.Timestamp != nil {
+		params["$time"] = u.Timestamp.Unix()
+	}
+	params[u.Operation] = u.Properties
+
+	bm.mu.Lock()
+	bm.profileUpdates = append(bm.profileUpdates, params)
+	bm.mu.Unlock()
+
+	return nil
+}
+
+// UnionUser queues a profile union to be sent in the next /engage batch.
+func (bm *BufferedMixpanel) UnionUser(userID string, u *Update) error {
+	params := map[string]interface{}{
+		"$token":       bm.Token,
+		"$distinct_id": userID,
+	}
+	params[u.Operation] = u.Properties
+
+	bm.mu.Lock()
+	bm.profileUpdates = append(bm.profileUpdates, params)
+	bm.mu.Unlock()
+
+	return nil
+}
+
+// UpdateGroup queues a group update to be sent in the next /groups batch.
+func (bm *BufferedMixpanel) UpdateGroup(groupKey, groupId string, u *Update) error {
+	params := map[string]interface{}{
+		"$token":     bm.Token,
+		"$group_id":  groupId,
+		"$group_key": groupKey,
+	}
+	params[u.Operation] = u.Properties
+
+	bm.mu.Lock()
+	bm.groupUpdates = append(bm.groupUpdates, params)
+	bm.mu.Unlock()
+
+	return nil
+}
+
+// UnionGroup queues a group union to be sent in the next /groups batch.
+func (bm *BufferedMixpanel) UnionGroup(groupKey, groupId string, u *Update) error {
+	params := map[string]interface{}{
+		"$token":     bm.Token,
+		"$group_id":  groupId,
+		"$group_key": groupKey,
+	}
+	params[u.Operation] = u.Properties
+
+	bm.mu.Lock()
+	bm.groupUpdates = append(bm.groupUpdates, params)
+	bm.mu.Unlock()
+
+	return nil
+}
+
+// The Context variants below only enqueue (no I/O happens until Flush), so
+// ctx is honored just by bailing out early if it's already done; it does not
+// otherwise affect queueing. Import is not overridden here and promotes from
+// the embedded *mixpanel, so ImportContext goes straight to Mixpanel
+// unbatched, same as Import.
+
+func (bm *BufferedMixpanel) TrackContext(ctx context.Context, distinctId, eventName string, e *Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bm.Track(distinctId, eventName, e)
+}
+
+func (bm *BufferedMixpanel) AliasContext(ctx context.Context, distinctId, newId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bm.Alias(distinctId, newId)
+}
+
+func (bm *BufferedMixpanel) UpdateUserContext(ctx context.Context, distinctId string, u *Update) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bm.UpdateUser(distinctId, u)
+}
+
+func (bm *BufferedMixpanel) UnionUserContext(ctx context.Context, userID string, u *Update) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bm.UnionUser(userID, u)
+}
+
+func (bm *BufferedMixpanel) UpdateGroupContext(ctx context.Context, groupKey, groupId string, u *Update) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bm.UpdateGroup(groupKey, groupId, u)
+}
+
+func (bm *BufferedMixpanel) UnionGroupContext(ctx context.Context, groupKey, groupId string, u *Update) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return bm.UnionGroup(groupKey, groupId, u)
+}
+
+// Flush sends everything currently queued, splitting it into batches that
+// respect MaxBatchSize and MaxBufferBytes. It returns the first error
+// encountered, but still attempts to flush every queue. Any chunk that fails
+// to send (even after retries) is requeued ahead of anything added since, so
+// a transient outage delays delivery rather than dropping events.
+func (bm *BufferedMixpanel) Flush(ctx context.Context) error {
+	bm.mu.Lock()
+	events := bm.events
+	profileUpdates := bm.profileUpdates
+	groupUpdates := bm.groupUpdates
+	bm.events = nil
+	bm.profileUpdates = nil
+	bm.groupUpdates = nil
+	bm.mu.Unlock()
+
+	var firstErr error
+	for _, batch := range []struct {
+		eventType string
+		items     []map[string]interface{}
+	}{
+		{"track", events},
+		{"engage", profileUpdates},
+		{"groups", groupUpdates},
+	} {
+		unsent, err := bm.flushBatch(ctx, batch.eventType, batch.items)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		bm.requeue(batch.eventType, unsent)
+	}
+
+	return firstErr
+}
+
+// flushBatch sends items in chunks. On the first chunk that fails to send,
+// it stops and returns that chunk plus everything after it, still unsent.
+func (bm *BufferedMixpanel) flushBatch(ctx context.Context, eventType string, items []map[string]interface{}) ([]map[string]interface{}, error) {
+	chunks := chunkBatch(items, bm.cfg.MaxBatchSize, bm.cfg.MaxBufferBytes)
+	for i, chunk := range chunks {
+		if err := bm.sendBatch(ctx, eventType, chunk); err != nil {
+			var unsent []map[string]interface{}
+			for _, c := range chunks[i:] {
+				unsent = append(unsent, c...)
+			}
+			return unsent, err
+		}
+	}
+	return nil, nil
+}
+
+// requeue prepends items back onto the buffer for eventType, ahead of
+// anything queued since the flush started.
+func (bm *BufferedMixpanel) requeue(eventType string, items []map[string]interface{}) {
+	if len(items) == 0 {
+		return
+	}
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	switch eventType {
+	case "track":
+		bm.events = append(items, bm.events...)
+	case "engage":
+		bm.profileUpdates = append(items, bm.profileUpdates...)
+	case "groups":
+		bm.groupUpdates = append(items, bm.groupUpdates...)
+	}
+}
+
+// chunkBatch splits items into groups of at most maxCount entries, further
+// splitting any group whose marshaled JSON would exceed maxBytes.
+func chunkBatch(items []map[string]interface{}, maxCount, maxBytes int) [][]map[string]interface{} {
+	var chunks [][]map[string]interface{}
+	for len(items) > 0 {
+		n := maxCount
+		if n > len(items) {
+			n = len(items)
+		}
+		for n > 1 {
+			if size, err := json.Marshal(items[:n]); err == nil && len(size) <= maxBytes {
+				break
+			}
+			n--
+		}
+		chunks = append(chunks, items[:n])
+		items = items[n:]
+	}
+	return chunks
+}
+
+func (bm *BufferedMixpanel) sendBatch(ctx context.Context, eventType string, items []map[string]interface{}) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	url := bm.ApiURL + "/" + eventType + "?verbose=1"
+
+	wrapErr := func(err error) error {
+		return &MixpanelError{URL: url, Err: err}
+	}
+
+	postBody, err := json.Marshal(items)
+	if err != nil {
+		return wrapErr(&ErrTrackFailed{Body: err.Error(), Resp: nil})
+	}
+	sendBody, contentEncoding := compressIfLarge(postBody)
+
+	if bm.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bm.timeout)
+		defer cancel()
+	}
+
+	buildReq := func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(sendBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if contentEncoding != "" {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		return req, nil
+	}
+
+	resp, body, err := bm.doWithRetry(ctx, buildReq)
+	if err != nil {
+		return wrapErr(&ErrTrackFailed{Body: err.Error(), Resp: resp})
+	}
+
+	var verbose verboseResponse
+	if err := json.Unmarshal(body, &verbose); err != nil {
+		if sb := string(body); sb != "1" {
+			return wrapErr(&ErrTrackFailed{Body: fmt.Sprintf("response not 1: %s", sb), Resp: resp})
+		}
+		return nil
+	}
+	if verbose.Status != 1 {
+		msg := verbose.Error
+		if msg == "" {
+			msg = string(body)
+		}
+		return wrapErr(&ErrTrackFailed{Body: msg, Resp: resp})
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package mixpanel
+
+import "testing"
+
+func TestChunkBatchRespectsMaxCount(t *testing.T) {
+	items := make([]map[string]interface{}, 125)
+	for i := range items {
+		items[i] = map[string]interface{}{"event": "e"}
+	}
+
+	chunks := chunkBatch(items, 50, 1<<20)
+
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	wantSizes := []int{50, 50, 25}
+	var total int
+	for i, c := range chunks {
+		if len(c) != wantSizes[i] {
+			t.Errorf("chunk %d: got %d items, want %d", i, len(c), wantSizes[i])
+		}
+		total += len(c)
+	}
+	if total != len(items) {
+		t.Errorf("got %d items across chunks, want %d", total, len(items))
+	}
+}
+
+func TestChunkBatchRespectsMaxBytes(t *testing.T) {
+	// Each item serializes to more than 20 bytes, so a 50-byte budget can
+	// never fit more than 2 per chunk (at 27 bytes an item, a chunk of two
+	// already marshals to 57 bytes, so each item goes out alone).
+	items := make([]map[string]interface{}, 5)
+	for i := range items {
+		items[i] = map[string]interface{}{"event": "some-event-name"}
+	}
+
+	chunks := chunkBatch(items, 50, 50)
+
+	for i, c := range chunks {
+		if len(c) > 2 {
+			t.Errorf("chunk %d has %d items, want at most 2 given the byte budget", i, len(c))
+		}
+	}
+
+	var total int
+	for _, c := range chunks {
+		total += len(c)
+	}
+	if total != len(items) {
+		t.Errorf("got %d items across chunks, want %d", total, len(items))
+	}
+}
+
+func TestChunkBatchNeverProducesEmptyChunk(t *testing.T) {
+	// Even if a single item exceeds maxBytes on its own, it must still go
+	// out alone rather than be dropped or loop forever.
+	items := []map[string]interface{}{
+		{"event": "this-single-item-is-too-big-for-the-byte-budget"},
+	}
+
+	chunks := chunkBatch(items, 50, 1)
+
+	if len(chunks) != 1 || len(chunks[0]) != 1 {
+		t.Fatalf("got %v, want a single chunk containing the one item", chunks)
+	}
+}
+
+func TestChunkBatchEmptyInput(t *testing.T) {
+	if chunks := chunkBatch(nil, 50, 1<<20); len(chunks) != 0 {
+		t.Fatalf("got %d chunks for empty input, want 0", len(chunks))
+	}
+}
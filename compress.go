@@ -0,0 +1,39 @@
+package mixpanel
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+// gzipThreshold is the payload size, in bytes, above which requests are
+// gzip-compressed before being sent. Small single-event payloads aren't
+// worth the gzip overhead, but batched imports and buffered flushes often
+// are.
+const gzipThreshold = 1024
+
+// gzipBytes gzip-compresses data.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressIfLarge gzip-compresses body when it's larger than gzipThreshold,
+// returning the (possibly compressed) bytes to send and the Content-Encoding
+// header value to set ("gzip", or "" if left uncompressed).
+func compressIfLarge(body []byte) ([]byte, string) {
+	if len(body) <= gzipThreshold {
+		return body, ""
+	}
+	compressed, err := gzipBytes(body)
+	if err != nil {
+		return body, ""
+	}
+	return compressed, "gzip"
+}
@@ -0,0 +1,46 @@
+package mixpanel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompressIfLargeLeavesSmallBodyUncompressed(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), gzipThreshold)
+	sent, encoding := compressIfLarge(body)
+
+	if encoding != "" {
+		t.Errorf("got encoding %q, want \"\" for a body at the threshold", encoding)
+	}
+	if !bytes.Equal(sent, body) {
+		t.Errorf("body was modified despite being left uncompressed")
+	}
+}
+
+func TestCompressIfLargeCompressesLargeBody(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), gzipThreshold+1)
+	sent, encoding := compressIfLarge(body)
+
+	if encoding != "gzip" {
+		t.Fatalf("got encoding %q, want gzip for a body over the threshold", encoding)
+	}
+	if len(sent) >= len(body) {
+		t.Errorf("got compressed size %d, want smaller than original %d", len(sent), len(body))
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(sent))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	defer gz.Close()
+
+	got, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading decompressed body: %v", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("decompressed body did not round-trip")
+	}
+}
@@ -0,0 +1,99 @@
+package mixpanel
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// redirectToTransport rewrites every request to target the given test
+// server, preserving path and query, since Export's URL is a fixed constant
+// rather than something derived from ApiURL.
+type redirectToTransport struct {
+	base *url.URL
+}
+
+func (rt redirectToTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.base.Scheme
+	req.URL.Host = rt.base.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newExportClient(srv *httptest.Server) Exporter {
+	base, _ := url.Parse(srv.URL)
+	client := &http.Client{Transport: redirectToTransport{base: base}}
+	return NewWithSecret("token", "secret", "12345", srv.URL, func(m *mixpanel) {
+		m.Client = client
+	}).(Exporter)
+}
+
+func TestExportStreamsEvents(t *testing.T) {
+	body := `{"event":"signed_up","properties":{"plan":"pro"}}
+{"event":"upgraded","properties":{"plan":"enterprise"}}
+`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	m := newExportClient(srv)
+	events, errc, err := m.Export(context.Background(), ExportParams{FromDate: "2020-01-01", ToDate: "2020-01-02"})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var got []ExportedEvent
+	for e := range events {
+		got = append(got, e)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	if len(got) != 2 || got[0].Event != "signed_up" || got[1].Event != "upgraded" {
+		t.Fatalf("got %+v, want signed_up then upgraded", got)
+	}
+}
+
+func TestExportStopsOnContextCancellation(t *testing.T) {
+	pr, pw := io.Pipe()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		w.Write([]byte(`{"event":"signed_up","properties":{}}` + "\n"))
+		flusher.Flush()
+		// Hang until the client disconnects, so the only way the test
+		// proceeds is via ctx cancellation breaking the read loop.
+		io.Copy(ioutil.Discard, pr)
+	}))
+	defer srv.Close()
+	defer pw.Close()
+
+	m := newExportClient(srv)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errc, err := m.Export(ctx, ExportParams{FromDate: "2020-01-01", ToDate: "2020-01-02"})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	if _, ok := <-events; !ok {
+		t.Fatal("expected to receive the first event before cancelling")
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("expected events channel to close after cancellation")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("events channel did not close after cancellation")
+	}
+	<-errc
+}
@@ -0,0 +1,56 @@
+package mixpanel
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestImportSendsProjectIDAndSecret(t *testing.T) {
+	var gotProjectID, gotUser, gotPass string
+	var gotBody []byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotProjectID = r.URL.Query().Get("project_id")
+		gotUser, gotPass, _ = r.BasicAuth()
+		gotBody, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{"code":200,"num_records_imported":1,"status":"OK"}`))
+	}))
+	defer srv.Close()
+
+	m := NewWithSecret("token", "secret", "12345", srv.URL)
+	err := m.Import("user-1", "signed_up", &Event{Properties: map[string]interface{}{"plan": "pro"}})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	if gotProjectID != "12345" {
+		t.Errorf("got project_id %q, want 12345", gotProjectID)
+	}
+	if gotUser != "secret" || gotPass != "" {
+		t.Errorf("got basic auth %q:%q, want secret:\"\"", gotUser, gotPass)
+	}
+
+	var payload []map[string]interface{}
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("unmarshal request body: %v", err)
+	}
+	if len(payload) != 1 || payload[0]["event"] != "signed_up" {
+		t.Errorf("got body %s, want a single signed_up event", gotBody)
+	}
+}
+
+func TestImportReturnsErrorOnNonOKStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"error":"invalid secret","status":"FAIL"}`))
+	}))
+	defer srv.Close()
+
+	m := NewWithSecret("token", "bad-secret", "12345", srv.URL)
+	err := m.Import("user-1", "signed_up", &Event{Properties: map[string]interface{}{"plan": "pro"}})
+	if err == nil {
+		t.Fatal("expected an error for a non-200 import code, got nil")
+	}
+}
@@ -2,9 +2,11 @@ package mixpanel
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"time"
 )
@@ -37,27 +39,87 @@ func (err *ErrTrackFailed) Error() string {
 type Mixpanel interface {
 	// Create a mixpanel event
 	Track(distinctId, eventName string, e *Event) error
+	TrackContext(ctx context.Context, distinctId, eventName string, e *Event) error
 
 	// Set properties for a mixpanel user.
 	UpdateUser(distinctId string, u *Update) error
+	UpdateUserContext(ctx context.Context, distinctId string, u *Update) error
 
 	// Set properties for a union on user
 	UnionUser(userID string, u *Update) error
+	UnionUserContext(ctx context.Context, userID string, u *Update) error
 
 	// Set properties for a mixpanel group.
 	UpdateGroup(groupKey, groupId string, u *Update) error
+	UpdateGroupContext(ctx context.Context, groupKey, groupId string, u *Update) error
 
 	// Set properties for a union on group
 	UnionGroup(groupKey, groupId string, u *Update) error
+	UnionGroupContext(ctx context.Context, groupKey, groupId string, u *Update) error
 
 	Alias(distinctId, newId string) error
+	AliasContext(ctx context.Context, distinctId, newId string) error
+
+	// Import backfills a historical event via the /import endpoint. Unlike
+	// Track, events older than ~5 days are accepted. Requires the project's
+	// API secret (see NewWithSecret).
+	Import(distinctId, eventName string, e *Event) error
+	ImportContext(ctx context.Context, distinctId, eventName string, e *Event) error
 }
 
 // The Mixapanel struct store the mixpanel endpoint and the project token
 type mixpanel struct {
 	Client *http.Client
 	Token  string
-	ApiURL string
+	Secret string
+	// ProjectID is the project's numeric ID, required by /import's
+	// project_id query param. It is distinct from Token. See NewWithSecret.
+	ProjectID string
+	ApiURL    string
+
+	// retryMaxAttempts and retryBaseDelay configure the opt-in retry policy
+	// set via WithRetry. retryMaxAttempts <= 1 means no retries.
+	retryMaxAttempts int
+	retryBaseDelay   time.Duration
+
+	// timeout, if set via WithTimeout, bounds every request's context.
+	timeout time.Duration
+}
+
+// Option configures a mixpanel client constructed via New, NewFromClient or
+// NewWithSecret.
+type Option func(*mixpanel)
+
+// WithRetry enables retrying 5xx responses and network errors with
+// exponential backoff and jitter, up to maxAttempts total tries (including
+// the first). baseDelay is the delay before the first retry; it roughly
+// doubles on each subsequent attempt.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(m *mixpanel) {
+		m.retryMaxAttempts = maxAttempts
+		m.retryBaseDelay = baseDelay
+	}
+}
+
+// WithTimeout bounds every non-streaming request (including retries) made by
+// the client to d. It does not apply to Export, whose streaming reads can
+// legitimately run far longer than a single request.
+func WithTimeout(d time.Duration) Option {
+	return func(m *mixpanel) {
+		m.timeout = d
+	}
+}
+
+// WithSecret sets the project's API secret, required for Import (and
+// Export). Prefer NewWithSecret when constructing a plain client; WithSecret
+// exists mainly so other constructors, like NewBuffered, can opt in too.
+// It sets only the secret, not the project ID that Import also sends, and no
+// Option sets that ID: a client built by New, NewFromClient or NewBuffered
+// sends Import with an empty project_id.
+func WithSecret(secret string) Option {
+	return func(m *mixpanel) {
+		m.Secret = secret
+	}
 }
 
 // A mixpanel event
@@ -92,6 +154,12 @@ type Update struct {
 
 // Track create a events to current distinct id
 func (m *mixpanel) Alias(distinctId, newId string) error {
+	return m.AliasContext(context.Background(), distinctId, newId)
+}
+
+// AliasContext is Alias, with ctx governing cancellation, deadlines and
+// retries.
+func (m *mixpanel) AliasContext(ctx context.Context, distinctId, newId string) error {
 	props := map[string]interface{}{
 		"token":       m.Token,
 		"distinct_id": distinctId,
@@ -103,11 +171,17 @@ func (m *mixpanel) Alias(distinctId, newId string) error {
 		"properties": props,
 	}
 
-	return m.sendPost("track", params)
+	return m.sendPost(ctx, "track", params)
 }
 
 // Track create a events to current distinct id
 func (m *mixpanel) Track(distinctId, eventName string, e *Event) error {
+	return m.TrackContext(context.Background(), distinctId, eventName, e)
+}
+
+// TrackContext is Track, with ctx governing cancellation, deadlines and
+// retries.
+func (m *mixpanel) TrackContext(ctx context.Context, distinctId, eventName string, e *Event) error {
 	props := map[string]interface{}{
 		"token":       m.Token,
 		"distinct_id": distinctId,
@@ -128,12 +202,18 @@ func (m *mixpanel) Track(distinctId, eventName string, e *Event) error {
 		"properties": props,
 	}
 
-	return m.sendPost("track", params)
+	return m.sendPost(ctx, "track", params)
 }
 
 // UpdateUser: Updates a user in mixpanel. See
 // https://mixpanel.com/help/reference/http#people-analytics-updates
 func (m *mixpanel) UpdateUser(distinctId string, u *Update) error {
+	return m.UpdateUserContext(context.Background(), distinctId, u)
+}
+
+// UpdateUserContext is UpdateUser, with ctx governing cancellation,
+// deadlines and retries.
+func (m *mixpanel) UpdateUserContext(ctx context.Context, distinctId string, u *Update) error {
 	params := map[string]interface{}{
 		"$token":       m.Token,
 		"$distinct_id": distinctId,
@@ -150,12 +230,18 @@ func (m *mixpanel) UpdateUser(distinctId string, u *Update) error {
 
 	params[u.Operation] = u.Properties
 
-	return m.sendPost("engage", params)
+	return m.sendPost(ctx, "engage", params)
 }
 
 // UnionGroup: Unions a group property in mixpanel. See
 // https://api.mixpanel.com/engage#profile-union
 func (m *mixpanel) UnionUser(userID string, u *Update) error {
+	return m.UnionUserContext(context.Background(), userID, u)
+}
+
+// UnionUserContext is UnionUser, with ctx governing cancellation, deadlines
+// and retries.
+func (m *mixpanel) UnionUserContext(ctx context.Context, userID string, u *Update) error {
 	params := map[string]interface{}{
 		"$token":       m.Token,
 		"$distinct_id": userID,
@@ -163,12 +249,18 @@ func (m *mixpanel) UnionUser(userID string, u *Update) error {
 
 	params[u.Operation] = u.Properties
 
-	return m.sendPost("engage#profile-union", params)
+	return m.sendPost(ctx, "engage#profile-union", params)
 }
 
 // UpdateUser: Updates a group in mixpanel. See
 // https://api.mixpanel.com/groups#group-set
 func (m *mixpanel) UpdateGroup(groupKey, groupId string, u *Update) error {
+	return m.UpdateGroupContext(context.Background(), groupKey, groupId, u)
+}
+
+// UpdateGroupContext is UpdateGroup, with ctx governing cancellation,
+// deadlines and retries.
+func (m *mixpanel) UpdateGroupContext(ctx context.Context, groupKey, groupId string, u *Update) error {
 	params := map[string]interface{}{
 		"$token":     m.Token,
 		"$group_id":  groupId,
@@ -177,12 +269,18 @@ func (m *mixpanel) UpdateGroup(groupKey, groupId string, u *Update) error {
 
 	params[u.Operation] = u.Properties
 
-	return m.sendPost("groups", params)
+	return m.sendPost(ctx, "groups", params)
 }
 
 // UnionGroup: Unions a group property in mixpanel. See
 // https://api.mixpanel.com/groups#group-union
 func (m *mixpanel) UnionGroup(groupKey, groupId string, u *Update) error {
+	return m.UnionGroupContext(context.Background(), groupKey, groupId, u)
+}
+
+// UnionGroupContext is UnionGroup, with ctx governing cancellation,
+// deadlines and retries.
+func (m *mixpanel) UnionGroupContext(ctx context.Context, groupKey, groupId string, u *Update) error {
 	params := map[string]interface{}{
 		"$token":     m.Token,
 		"$group_id":  groupId,
@@ -191,13 +289,21 @@ func (m *mixpanel) UnionGroup(groupKey, groupId string, u *Update) error {
 
 	params[u.Operation] = u.Properties
 
-	return m.sendPost("groups#group-union", params)
+	return m.sendPost(ctx, "groups#group-union", params)
 }
 
-func (m *mixpanel) sendPost(eventType string, params interface{}) error {
+// verboseResponse is the JSON body returned when verbose=1 is set on
+// /track, /engage or /groups, e.g. {"status":1} or
+// {"status":0,"error":"..."}.
+type verboseResponse struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+func (m *mixpanel) sendPost(ctx context.Context, eventType string, params interface{}) error {
 	// params needs to be an array
 	params = []interface{}{params}
-	url := m.ApiURL + "/" + eventType
+	url := m.ApiURL + "/" + eventType + "?verbose=1"
 
 	wrapErr := func(err error) error {
 		return &MixpanelError{URL: url, Err: err}
@@ -205,47 +311,158 @@ func (m *mixpanel) sendPost(eventType string, params interface{}) error {
 
 	postBody, err := json.Marshal(params)
 	if err != nil {
-		wrapErr(&ErrTrackFailed{Body: err.Error(), Resp: nil})
+		return wrapErr(&ErrTrackFailed{Body: err.Error(), Resp: nil})
 	}
+	sendBody, contentEncoding := compressIfLarge(postBody)
 
-	responseBody := bytes.NewBuffer(postBody)
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
 
-	//Leverage Go's HTTP Post function to make request
-	resp, err := http.Post(url, "application/json", responseBody)
-	//Handle Error
-	if err != nil {
-		wrapErr(&ErrTrackFailed{Body: err.Error(), Resp: resp})
+	buildReq := func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(sendBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if contentEncoding != "" {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		return req, nil
 	}
-	defer resp.Body.Close()
-	//Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
+
+	resp, body, err := m.doWithRetry(ctx, buildReq)
 	if err != nil {
-		wrapErr(&ErrTrackFailed{Body: err.Error(), Resp: resp})
+		return wrapErr(&ErrTrackFailed{Body: err.Error(), Resp: resp})
+	}
+
+	var verbose verboseResponse
+	if err := json.Unmarshal(body, &verbose); err != nil {
+		// Fall back to the pre-verbose plain "1" response in case the
+		// endpoint doesn't honor verbose=1.
+		if string(body) != "1" {
+			return wrapErr(&ErrTrackFailed{Body: "response not 1", Resp: resp})
+		}
+		return nil
 	}
-	sb := string(body)
-	if sb != "1" {
-		return wrapErr(&ErrTrackFailed{Body: "response not 1", Resp: resp})
+	if verbose.Status != 1 {
+		msg := verbose.Error
+		if msg == "" {
+			msg = string(body)
+		}
+		return wrapErr(&ErrTrackFailed{Body: msg, Resp: resp})
 	}
 
 	return nil
 }
 
+// doWithRetry issues the request built by buildReq against m.Client,
+// retrying 5xx responses and network errors per m.retryMaxAttempts /
+// m.retryBaseDelay. With no retry policy configured, it tries exactly once.
+func (m *mixpanel) doWithRetry(ctx context.Context, buildReq func(ctx context.Context) (*http.Request, error)) (*http.Response, []byte, error) {
+	maxAttempts := m.retryMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	baseDelay := m.retryBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = 200 * time.Millisecond
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoffDelay(baseDelay, attempt)):
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+
+		req, err := buildReq(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, err := m.Client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("mixpanel: server error %d: %s", resp.StatusCode, string(body))
+			continue
+		}
+
+		return resp, body, nil
+	}
+
+	return nil, nil, lastErr
+}
+
+// backoffDelay returns the delay before the given retry attempt (1 being the
+// first retry), as exponential backoff off of base with up to 50% jitter.
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt-1)
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
 // New returns the client instance. If apiURL is blank, the default will be used
-// ("https://api.mixpanel.com").
-func New(token, apiURL string) Mixpanel {
-	return NewFromClient(http.DefaultClient, token, apiURL)
+// ("https://api.mixpanel.com"). opts configures optional behaviour such as
+// WithRetry and WithTimeout.
+func New(token, apiURL string, opts ...Option) Mixpanel {
+	return NewFromClient(http.DefaultClient, token, apiURL, opts...)
 }
 
 // Creates a client instance using the specified client instance. This is useful
 // when using a proxy.
-func NewFromClient(c *http.Client, token, apiURL string) Mixpanel {
+func NewFromClient(c *http.Client, token, apiURL string, opts ...Option) Mixpanel {
 	if apiURL == "" {
 		apiURL = "https://api.mixpanel.com"
 	}
 
-	return &mixpanel{
+	m := &mixpanel{
 		Client: c,
 		Token:  token,
 		ApiURL: apiURL,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// NewWithSecret returns a client instance that also carries the project's API
+// secret and numeric project ID, required for Import (and other endpoints
+// authenticated with HTTP Basic auth rather than the project token). Find
+// projectID on the project settings page; it is not the same as token. If
+// apiURL is blank, the default will be used ("https://api.mixpanel.com").
+func NewWithSecret(token, secret, projectID, apiURL string, opts ...Option) Mixpanel {
+	if apiURL == "" {
+		apiURL = "https://api.mixpanel.com"
+	}
+
+	m := &mixpanel{
+		Client:    http.DefaultClient,
+		Token:     token,
+		Secret:    secret,
+		ProjectID: projectID,
+		ApiURL:    apiURL,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
 }
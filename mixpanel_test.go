@@ -0,0 +1,72 @@
+package mixpanel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTrackSucceedsOnVerboseStatusOne(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":1}`))
+	}))
+	defer srv.Close()
+
+	m := New("token", srv.URL)
+	if err := m.Track("user-1", "signed_up", &Event{Properties: map[string]interface{}{"plan": "pro"}}); err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+}
+
+func TestTrackRetriesOnServerErrorThenSucceeds(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"status":1}`))
+	}))
+	defer srv.Close()
+
+	m := New("token", srv.URL, WithRetry(5, time.Millisecond))
+	if err := m.Track("user-1", "signed_up", &Event{Properties: map[string]interface{}{"plan": "pro"}}); err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts, want 3", got)
+	}
+}
+
+func TestTrackGivesUpAfterMaxAttempts(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	m := New("token", srv.URL, WithRetry(3, time.Millisecond))
+	if err := m.Track("user-1", "signed_up", &Event{Properties: map[string]interface{}{"plan": "pro"}}); err == nil {
+		t.Fatal("expected an error after exhausting retries, got nil")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts, want 3 (maxAttempts)", got)
+	}
+}
+
+func TestTrackHonorsWithTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"status":1}`))
+	}))
+	defer srv.Close()
+
+	m := New("token", srv.URL, WithTimeout(5*time.Millisecond))
+	err := m.Track("user-1", "signed_up", &Event{Properties: map[string]interface{}{"plan": "pro"}})
+	if err == nil {
+		t.Fatal("expected a timeout error, got nil")
+	}
+}
@@ -0,0 +1,274 @@
+package mixpanel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+var _ Mixpanel = (*Mock)(nil)
+
+// MockEvent records a single call to Track or Import against a Mock.
+type MockEvent struct {
+	DistinctId string
+	Name       string
+	Properties map[string]interface{}
+}
+
+// MockPeople is the recorded profile state for a single distinct ID.
+type MockPeople struct {
+	DistinctId string
+	Properties map[string]interface{}
+}
+
+// MockGroup is the recorded profile state for a single group.
+type MockGroup struct {
+	GroupKey   string
+	GroupId    string
+	Properties map[string]interface{}
+}
+
+// Mock is an in-memory implementation of the Mixpanel interface for use in
+// tests. It makes no HTTP calls; instead it records every Track, Import,
+// UpdateUser, UnionUser, UpdateGroup, UnionGroup and Alias call so tests can
+// assert against it directly, e.g. mock.People["user-1"].Properties["$email"].
+//
+// A Mock is safe for concurrent use.
+type Mock struct {
+	mu sync.Mutex
+
+	Events  []MockEvent
+	Imports []MockEvent
+	People  map[string]*MockPeople
+	Groups  map[string]*MockGroup
+	Aliases map[string]string
+}
+
+// NewMock returns an empty Mock ready to record calls.
+func NewMock() *Mock {
+	return &Mock{
+		People:  make(map[string]*MockPeople),
+		Groups:  make(map[string]*MockGroup),
+		Aliases: make(map[string]string),
+	}
+}
+
+func eventProperties(e *Event) map[string]interface{} {
+	props := make(map[string]interface{}, len(e.Properties)+2)
+	if e.IP != "" {
+		props["ip"] = e.IP
+	}
+	if e.Timestamp != nil {
+		props["time"] = e.Timestamp.Unix()
+	}
+	for key, value := range e.Properties {
+		props[key] = value
+	}
+	return props
+}
+
+// Track records the event under Events.
+func (m *Mock) Track(distinctId, eventName string, e *Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Events = append(m.Events, MockEvent{
+		DistinctId: distinctId,
+		Name:       eventName,
+		Properties: eventProperties(e),
+	})
+	return nil
+}
+
+// Import records the event under Imports, separately from Events sent via
+// Track, since Import backfills historical data rather than live events.
+func (m *Mock) Import(distinctId, eventName string, e *Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Imports = append(m.Imports, MockEvent{
+		DistinctId: distinctId,
+		Name:       eventName,
+		Properties: eventProperties(e),
+	})
+	return nil
+}
+
+// Alias records newId as an alias of distinctId.
+func (m *Mock) Alias(distinctId, newId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Aliases[distinctId] = newId
+	return nil
+}
+
+func (m *Mock) people(distinctId string) *MockPeople {
+	p, ok := m.People[distinctId]
+	if !ok {
+		p = &MockPeople{DistinctId: distinctId, Properties: make(map[string]interface{})}
+		m.People[distinctId] = p
+	}
+	return p
+}
+
+func (m *Mock) group(groupKey, groupId string) *MockGroup {
+	key := groupKey + ":" + groupId
+	g, ok := m.Groups[key]
+	if !ok {
+		g = &MockGroup{GroupKey: groupKey, GroupId: groupId, Properties: make(map[string]interface{})}
+		m.Groups[key] = g
+	}
+	return g
+}
+
+// applyOperation applies an Update's operation onto target in place. It
+// covers the operations Mixpanel documents for people/group profiles; it is
+// not a full re-implementation of Mixpanel's people-analytics semantics
+// (e.g. $add only handles numeric properties), but it does not silently
+// mishandle operations it doesn't understand — an unrecognized operation is
+// an error rather than a quiet overwrite, since a mock that produces wrong
+// state is worse than one that fails loudly.
+func applyOperation(target map[string]interface{}, operation string, props map[string]interface{}) error {
+	switch operation {
+	case "$set":
+		for k, v := range props {
+			target[k] = v
+		}
+	case "$set_once":
+		for k, v := range props {
+			if _, exists := target[k]; !exists {
+				target[k] = v
+			}
+		}
+	case "$unset":
+		for k := range props {
+			delete(target, k)
+		}
+	case "$add":
+		for k, v := range props {
+			delta, ok := toFloat(v)
+			if !ok {
+				target[k] = v
+				continue
+			}
+			if existing, ok := toFloat(target[k]); ok {
+				target[k] = existing + delta
+				continue
+			}
+			target[k] = delta
+		}
+	case "$append":
+		for k, v := range props {
+			existing, _ := target[k].([]interface{})
+			target[k] = append(existing, v)
+		}
+	case "$union":
+		for k, v := range props {
+			existing, _ := target[k].([]interface{})
+			target[k] = append(existing, v)
+		}
+	case "$remove":
+		for k, v := range props {
+			existing, ok := target[k].([]interface{})
+			if !ok {
+				continue
+			}
+			out := existing[:0]
+			removed := false
+			for _, item := range existing {
+				if !removed && item == v {
+					removed = true
+					continue
+				}
+				out = append(out, item)
+			}
+			target[k] = out
+		}
+	default:
+		return fmt.Errorf("mixpanel: Mock does not support update operation %q", operation)
+	}
+	return nil
+}
+
+// toFloat coerces numeric JSON-ish values (as produced by callers building
+// Update.Properties by hand) to a float64 for $add.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// UpdateUser applies u to the recorded profile for distinctId.
+func (m *Mock) UpdateUser(distinctId string, u *Update) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return applyOperation(m.people(distinctId).Properties, u.Operation, u.Properties)
+}
+
+// UnionUser applies u to the recorded profile for userID.
+func (m *Mock) UnionUser(userID string, u *Update) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return applyOperation(m.people(userID).Properties, u.Operation, u.Properties)
+}
+
+// UpdateGroup applies u to the recorded profile for the group.
+func (m *Mock) UpdateGroup(groupKey, groupId string, u *Update) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return applyOperation(m.group(groupKey, groupId).Properties, u.Operation, u.Properties)
+}
+
+// UnionGroup applies u to the recorded profile for the group.
+func (m *Mock) UnionGroup(groupKey, groupId string, u *Update) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return applyOperation(m.group(groupKey, groupId).Properties, u.Operation, u.Properties)
+}
+
+// The Context variants below record identically; ctx is accepted only to
+// satisfy the Mixpanel interface, since recording never blocks on I/O.
+
+func (m *Mock) TrackContext(ctx context.Context, distinctId, eventName string, e *Event) error {
+	return m.Track(distinctId, eventName, e)
+}
+
+func (m *Mock) ImportContext(ctx context.Context, distinctId, eventName string, e *Event) error {
+	return m.Import(distinctId, eventName, e)
+}
+
+func (m *Mock) AliasContext(ctx context.Context, distinctId, newId string) error {
+	return m.Alias(distinctId, newId)
+}
+
+func (m *Mock) UpdateUserContext(ctx context.Context, distinctId string, u *Update) error {
+	return m.UpdateUser(distinctId, u)
+}
+
+func (m *Mock) UnionUserContext(ctx context.Context, userID string, u *Update) error {
+	return m.UnionUser(userID, u)
+}
+
+func (m *Mock) UpdateGroupContext(ctx context.Context, groupKey, groupId string, u *Update) error {
+	return m.UpdateGroup(groupKey, groupId, u)
+}
+
+func (m *Mock) UnionGroupContext(ctx context.Context, groupKey, groupId string, u *Update) error {
+	return m.UnionGroup(groupKey, groupId, u)
+}
@@ -0,0 +1,129 @@
+package mixpanel
+
+import "testing"
+
+func TestApplyOperationSet(t *testing.T) {
+	target := map[string]interface{}{"plan": "free"}
+	if err := applyOperation(target, "$set", map[string]interface{}{"plan": "pro"}); err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	if target["plan"] != "pro" {
+		t.Errorf("got plan %v, want pro", target["plan"])
+	}
+}
+
+func TestApplyOperationSetOnce(t *testing.T) {
+	target := map[string]interface{}{"signup_source": "web"}
+	err := applyOperation(target, "$set_once", map[string]interface{}{
+		"signup_source": "mobile",
+		"referrer":      "ad",
+	})
+	if err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	if target["signup_source"] != "web" {
+		t.Errorf("got signup_source %v, want web (existing value preserved)", target["signup_source"])
+	}
+	if target["referrer"] != "ad" {
+		t.Errorf("got referrer %v, want ad (new key set)", target["referrer"])
+	}
+}
+
+func TestApplyOperationUnset(t *testing.T) {
+	target := map[string]interface{}{"plan": "pro", "trial": true}
+	if err := applyOperation(target, "$unset", map[string]interface{}{"trial": nil}); err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	if _, ok := target["trial"]; ok {
+		t.Errorf("trial still present after $unset")
+	}
+	if target["plan"] != "pro" {
+		t.Errorf("$unset touched an unrelated key")
+	}
+}
+
+func TestApplyOperationAdd(t *testing.T) {
+	target := map[string]interface{}{"credits": float64(10)}
+	if err := applyOperation(target, "$add", map[string]interface{}{"credits": 5}); err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	if target["credits"] != float64(15) {
+		t.Errorf("got credits %v, want 15", target["credits"])
+	}
+}
+
+func TestApplyOperationAddOnMissingKey(t *testing.T) {
+	target := map[string]interface{}{}
+	if err := applyOperation(target, "$add", map[string]interface{}{"credits": 3}); err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	if target["credits"] != float64(3) {
+		t.Errorf("got credits %v, want 3", target["credits"])
+	}
+}
+
+func TestApplyOperationAppend(t *testing.T) {
+	target := map[string]interface{}{"tags": []interface{}{"a"}}
+	if err := applyOperation(target, "$append", map[string]interface{}{"tags": "b"}); err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	got, _ := target["tags"].([]interface{})
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("got tags %v, want [a b]", got)
+	}
+}
+
+func TestApplyOperationUnion(t *testing.T) {
+	target := map[string]interface{}{"plans": []interface{}{"free"}}
+	if err := applyOperation(target, "$union", map[string]interface{}{"plans": "pro"}); err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	got, _ := target["plans"].([]interface{})
+	if len(got) != 2 || got[0] != "free" || got[1] != "pro" {
+		t.Errorf("got plans %v, want [free pro]", got)
+	}
+}
+
+func TestApplyOperationRemove(t *testing.T) {
+	target := map[string]interface{}{"tags": []interface{}{"a", "b", "c"}}
+	if err := applyOperation(target, "$remove", map[string]interface{}{"tags": "b"}); err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	got, _ := target["tags"].([]interface{})
+	if len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Errorf("got tags %v, want [a c]", got)
+	}
+}
+
+func TestApplyOperationRemoveMissingKey(t *testing.T) {
+	target := map[string]interface{}{}
+	if err := applyOperation(target, "$remove", map[string]interface{}{"tags": "b"}); err != nil {
+		t.Fatalf("applyOperation: %v", err)
+	}
+	if _, ok := target["tags"]; ok {
+		t.Errorf("$remove on a missing key should not create it")
+	}
+}
+
+func TestApplyOperationUnknown(t *testing.T) {
+	err := applyOperation(map[string]interface{}{}, "$bogus", map[string]interface{}{"x": 1})
+	if err == nil {
+		t.Fatal("expected an error for an unknown operation, got nil")
+	}
+}
+
+func TestMockUpdateUserPropagatesError(t *testing.T) {
+	m := NewMock()
+	err := m.UpdateUser("user-1", &Update{Operation: "$bogus", Properties: map[string]interface{}{"x": 1}})
+	if err == nil {
+		t.Fatal("expected UpdateUser to propagate the applyOperation error, got nil")
+	}
+}
+
+func TestMockUpdateGroupPropagatesError(t *testing.T) {
+	m := NewMock()
+	err := m.UpdateGroup("plan", "pro", &Update{Operation: "$bogus", Properties: map[string]interface{}{"x": 1}})
+	if err == nil {
+		t.Fatal("expected UpdateGroup to propagate the applyOperation error, got nil")
+	}
+}